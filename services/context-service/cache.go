@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+)
+
+// Cache entries are stored with two TTLs: a soft TTL after which a read
+// still gets served (stale) while a refresh happens in the background,
+// and a hard TTL, which is what Redis actually expires the key at. This
+// keeps p99 latency flat across the soft TTL boundary instead of every
+// handler blocking on Postgres right when a popular key goes stale.
+func cacheSoftTTL() time.Duration {
+	return getDurationEnv("CACHE_SOFT_TTL", 5*time.Minute)
+}
+
+func cacheHardTTL() time.Duration {
+	return getDurationEnv("CACHE_HARD_TTL", 30*time.Minute)
+}
+
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	raw := getEnv(key, "")
+	if raw == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return defaultValue
+	}
+	return d
+}
+
+// cacheGroup deduplicates concurrent cache fills/refreshes for the same
+// key so only one goroutine per key ever hits Postgres on a miss.
+var cacheGroup singleflight.Group
+
+// cacheEntry wraps a cached value with the time it was stored, so a read
+// can tell whether it's past its soft TTL even though Redis only tracks
+// the hard TTL via key expiry.
+type cacheEntry struct {
+	StoredAt time.Time       `json:"stored_at"`
+	Data     json.RawMessage `json:"data"`
+}
+
+// fetchWithCache serves cacheKey from Redis if present. A fresh hit is
+// returned as-is; a stale hit (past cacheSoftTTL) is returned immediately
+// and a background refresh is kicked off guarded by singleflight. On a
+// full miss, fill is called (again via singleflight) to populate the
+// cache under ttl.
+func (a *App) fetchWithCache(cacheKey string, ttl time.Duration, fill func() (ContextResponse, error)) (ContextResponse, error) {
+	entry, found, stale := a.getFromCache(cacheKey)
+	if found {
+		var response ContextResponse
+		if err := json.Unmarshal(entry.Data, &response); err == nil {
+			response.Cached = true
+			if stale {
+				go a.refreshCache(cacheKey, ttl, fill)
+			}
+			return response, nil
+		}
+	}
+
+	v, err, _ := cacheGroup.Do(cacheKey, func() (interface{}, error) {
+		response, err := fill()
+		if err != nil {
+			return ContextResponse{}, err
+		}
+		a.setCache(cacheKey, response, ttl)
+		return response, nil
+	})
+	if err != nil {
+		return ContextResponse{}, err
+	}
+
+	return v.(ContextResponse), nil
+}
+
+// refreshCache repopulates cacheKey in the background for a stale read.
+// Sharing cacheGroup with fetchWithCache means a stale read that races a
+// concurrent miss on the same key only triggers one Postgres query.
+func (a *App) refreshCache(cacheKey string, ttl time.Duration, fill func() (ContextResponse, error)) {
+	_, _, _ = cacheGroup.Do(cacheKey, func() (interface{}, error) {
+		response, err := fill()
+		if err != nil {
+			log.Printf("Cache refresh for %q failed: %v", cacheKey, err)
+			return ContextResponse{}, err
+		}
+		a.setCache(cacheKey, response, ttl)
+		return response, nil
+	})
+}
+
+// getFromCache returns the decoded entry, whether it was found, and
+// whether it's past its soft TTL.
+func (a *App) getFromCache(key string) (entry cacheEntry, found bool, stale bool) {
+	if a.redis == nil {
+		return cacheEntry{}, false, false
+	}
+
+	val, err := a.redis.Get(ctx, key).Result()
+	if err != nil {
+		return cacheEntry{}, false, false
+	}
+
+	if err := json.Unmarshal([]byte(val), &entry); err != nil {
+		return cacheEntry{}, false, false
+	}
+
+	return entry, true, time.Since(entry.StoredAt) > cacheSoftTTL()
+}
+
+func (a *App) setCache(key string, value ContextResponse, ttl time.Duration) {
+	if a.redis == nil {
+		return
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		log.Printf("Cache encode error: %v", err)
+		return
+	}
+
+	entry, err := json.Marshal(cacheEntry{StoredAt: time.Now(), Data: data})
+	if err != nil {
+		log.Printf("Cache encode error: %v", err)
+		return
+	}
+
+	if err := a.redis.Set(ctx, key, entry, ttl).Err(); err != nil {
+		log.Printf("Cache set error: %v", err)
+	}
+}
+
+// startInvalidationSubscriber runs until ctx is cancelled, listening on
+// invalidationChannel and dropping the cache keys a write could have
+// affected. It's how a write on one instance (or the ingest worker pool)
+// keeps every other instance's cache from serving a stale row for the
+// rest of its hard TTL.
+func (a *App) startInvalidationSubscriber(ctx context.Context) {
+	if a.redis == nil {
+		return
+	}
+
+	sub := a.redis.Subscribe(ctx, invalidationChannel)
+	ch := sub.Channel()
+
+	go func() {
+		defer sub.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+				var m invalidationMessage
+				if err := json.Unmarshal([]byte(msg.Payload), &m); err != nil {
+					log.Printf("invalidation subscriber: bad message: %v", err)
+					continue
+				}
+				a.invalidateCache(ctx, m)
+			}
+		}
+	}()
+}
+
+// invalidateCache drops every cache key a row in category could appear
+// under. It also always sweeps the unfiltered ("no category" query) keys,
+// since every row - regardless of its own category - contributes to those
+// results too; without this, the most common query shape would keep
+// serving a stale response for the full hard TTL after every write. The
+// rolling window pools all categories together, so any invalidation
+// clears it too.
+func (a *App) invalidateCache(ctx context.Context, m invalidationMessage) {
+	if m.Category != "" {
+		a.deleteByPattern(ctx, fmt.Sprintf("context:%s:*", m.Category))
+		a.deleteByPattern(ctx, fmt.Sprintf("recent_context:*:%s", m.Category))
+	}
+
+	a.deleteByPattern(ctx, "context::*")
+	a.deleteByPattern(ctx, "recent_context:*:")
+	a.deleteByPattern(ctx, "rolling:*")
+
+	if a.rolling != nil {
+		a.rolling.TriggerRefresh()
+	}
+}
+
+// deleteByPattern walks matching keys with SCAN (never the O(N)-blocking
+// KEYS) and deletes them in the small batches SCAN hands back. SCAN has
+// no key argument to route by, so against a Cluster client it only
+// visits whichever single master go-redis happens to pick - this fans it
+// out across every master explicitly so no shard is skipped.
+func (a *App) deleteByPattern(ctx context.Context, pattern string) {
+	if a.redis == nil {
+		return
+	}
+
+	if cluster, ok := a.redis.(*redis.ClusterClient); ok {
+		if err := cluster.ForEachMaster(ctx, func(ctx context.Context, shard *redis.Client) error {
+			return scanAndDelete(ctx, shard, pattern)
+		}); err != nil {
+			log.Printf("cache invalidation fan-out for %q failed: %v", pattern, err)
+		}
+		return
+	}
+
+	if err := scanAndDelete(ctx, a.redis, pattern); err != nil {
+		log.Printf("cache invalidation scan for %q failed: %v", pattern, err)
+	}
+}
+
+// scanAndDelete SCANs a single node (or a non-Cluster client) for pattern
+// and deletes every match.
+func scanAndDelete(ctx context.Context, client redis.Cmdable, pattern string) error {
+	var cursor uint64
+	for {
+		keys, next, err := client.Scan(ctx, cursor, pattern, 100).Result()
+		if err != nil {
+			return err
+		}
+
+		if len(keys) > 0 {
+			if err := client.Del(ctx, keys...).Err(); err != nil {
+				return err
+			}
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestGetDurationEnv(t *testing.T) {
+	const key = "CONTEXT_SERVICE_TEST_DURATION"
+	os.Unsetenv(key)
+
+	if got := getDurationEnv(key, 2*time.Minute); got != 2*time.Minute {
+		t.Errorf("unset env: got %v, want default 2m", got)
+	}
+
+	os.Setenv(key, "45s")
+	defer os.Unsetenv(key)
+	if got := getDurationEnv(key, 2*time.Minute); got != 45*time.Second {
+		t.Errorf("valid env: got %v, want 45s", got)
+	}
+
+	os.Setenv(key, "not-a-duration")
+	if got := getDurationEnv(key, 2*time.Minute); got != 2*time.Minute {
+		t.Errorf("invalid env: got %v, want default 2m", got)
+	}
+}
+
+func TestCacheSoftAndHardTTLDefaults(t *testing.T) {
+	os.Unsetenv("CACHE_SOFT_TTL")
+	os.Unsetenv("CACHE_HARD_TTL")
+
+	if got := cacheSoftTTL(); got != 5*time.Minute {
+		t.Errorf("cacheSoftTTL() = %v, want 5m", got)
+	}
+	if got := cacheHardTTL(); got != 30*time.Minute {
+		t.Errorf("cacheHardTTL() = %v, want 30m", got)
+	}
+	if cacheSoftTTL() >= cacheHardTTL() {
+		t.Error("soft TTL must be shorter than hard TTL for stale-while-revalidate to do anything")
+	}
+}
+
+func TestCacheEntryStaleness(t *testing.T) {
+	fresh := cacheEntry{StoredAt: time.Now()}
+	if time.Since(fresh.StoredAt) > cacheSoftTTL() {
+		t.Error("freshly stored entry should not be stale")
+	}
+
+	stale := cacheEntry{StoredAt: time.Now().Add(-cacheSoftTTL() - time.Second)}
+	if time.Since(stale.StoredAt) <= cacheSoftTTL() {
+		t.Error("entry older than the soft TTL should be stale")
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// IngestWorkerPool pops items off the ingestion queue, validates and
+// writes them to Postgres inside a transaction, and on commit publishes a
+// cache-invalidation message so every instance can drop stale entries.
+type IngestWorkerPool struct {
+	app     *App
+	queue   Queue
+	workers int
+
+	processed int64
+	lastPopAt atomic.Value // time.Time
+}
+
+func NewIngestWorkerPool(app *App, queue Queue, workers int) *IngestWorkerPool {
+	if workers < 1 {
+		workers = 1
+	}
+	return &IngestWorkerPool{app: app, queue: queue, workers: workers}
+}
+
+// Start launches the worker goroutines. They run until ctx is cancelled.
+func (p *IngestWorkerPool) Start(ctx context.Context) {
+	for i := 0; i < p.workers; i++ {
+		go p.run(ctx, i)
+	}
+}
+
+func (p *IngestWorkerPool) run(ctx context.Context, id int) {
+	for {
+		item, err := p.queue.Pop(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("ingest worker %d: pop failed: %v", id, err)
+			time.Sleep(time.Second)
+			continue
+		}
+
+		p.lastPopAt.Store(time.Now())
+		item.Attempts++
+
+		if err := p.process(ctx, item); err != nil {
+			log.Printf("ingest worker %d: processing %q item (attempt %d) failed: %v", id, item.Op, item.Attempts, err)
+
+			if item.Attempts >= ingestMaxAttempts() {
+				if dl, ok := p.queue.(interface {
+					DeadLetter(ctx context.Context, item QueueItem) error
+				}); ok {
+					if err := dl.DeadLetter(ctx, item); err != nil {
+						log.Printf("ingest worker %d: dead-letter failed: %v", id, err)
+					} else {
+						log.Printf("ingest worker %d: %q item for id %d exceeded %d attempts, dead-lettered", id, item.Op, item.ID, ingestMaxAttempts())
+					}
+				}
+			} else if retryer, ok := p.queue.(interface {
+				Retry(ctx context.Context, item QueueItem) error
+			}); ok {
+				// Persist the bumped attempt count so it survives the
+				// item sitting on the processing list until the next
+				// crash-recovery cycle re-delivers it, instead of
+				// resetting to 0 every time.
+				if err := retryer.Retry(ctx, item); err != nil {
+					log.Printf("ingest worker %d: persisting retry count failed: %v", id, err)
+				}
+			}
+			continue
+		}
+
+		if acker, ok := p.queue.(interface {
+			Ack(ctx context.Context, item QueueItem) error
+		}); ok {
+			if err := acker.Ack(ctx, item); err != nil {
+				log.Printf("ingest worker %d: ack failed: %v", id, err)
+			}
+		}
+
+		atomic.AddInt64(&p.processed, 1)
+	}
+}
+
+// ingestMaxAttempts is how many attempts a failing item gets in total (the
+// first one plus retries via crash-recovery restarts re-delivering it)
+// before it's moved to the dead-letter list instead of looping forever.
+func ingestMaxAttempts() int {
+	return getIntEnv("INGEST_MAX_ATTEMPTS", 5)
+}
+
+func (p *IngestWorkerPool) process(ctx context.Context, item QueueItem) error {
+	tx, err := p.app.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var category, source string
+
+	switch item.Op {
+	case "insert":
+		if item.Context == nil {
+			return fmt.Errorf("insert item missing context payload")
+		}
+		if err := validateContext(item.Context); err != nil {
+			return err
+		}
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO context (category, content, source, is_active, priority, expires_at)
+			VALUES ($1, $2, $3, true, $4, $5)
+			RETURNING id
+		`, item.Context.Category, item.Context.Content, item.Context.Source, item.Context.Priority, item.Context.ExpiresAt,
+		).Scan(&item.Context.ID)
+		if err != nil {
+			return err
+		}
+		category, source = item.Context.Category, item.Context.Source
+
+	case "update":
+		if item.Patch == nil {
+			return fmt.Errorf("update item missing patch payload")
+		}
+		if err := validateContextPatch(item.Patch); err != nil {
+			return err
+		}
+		// COALESCE applies the patch directly in Postgres rather than
+		// against a row snapshot read earlier in the handler, so two
+		// concurrent patches to the same id can't silently drop each
+		// other's change - whichever commits second layers its delta on
+		// top of the first's, instead of overwriting it.
+		err := tx.QueryRowContext(ctx, `
+			UPDATE context SET
+				category   = COALESCE($1, category),
+				content    = COALESCE($2, content),
+				source     = COALESCE($3, source),
+				priority   = COALESCE($4, priority),
+				expires_at = COALESCE($5, expires_at)
+			WHERE id = $6
+			RETURNING category, source
+		`, item.Patch.Category, item.Patch.Content, item.Patch.Source, item.Patch.Priority, item.Patch.ExpiresAt, item.ID,
+		).Scan(&category, &source)
+		if err != nil {
+			return fmt.Errorf("update id %d: %w", item.ID, err)
+		}
+
+	case "delete":
+		err := tx.QueryRowContext(ctx, `
+			UPDATE context SET is_active = false WHERE id = $1
+			RETURNING category, source
+		`, item.ID).Scan(&category, &source)
+		if err != nil {
+			return err
+		}
+
+	default:
+		return fmt.Errorf("unknown queue op %q", item.Op)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	p.app.publishInvalidation(ctx, category, source)
+	return nil
+}
+
+// Stats reports the current queue depth and how long it's been since a
+// worker last popped an item, for /health.
+func (p *IngestWorkerPool) Stats(ctx context.Context) (depth int64, lag time.Duration) {
+	depth, _ = p.queue.Len(ctx)
+	if t, ok := p.lastPopAt.Load().(time.Time); ok {
+		lag = time.Since(t)
+	}
+	return depth, lag
+}
+
+// BacklogStats reports how many items are checked out for processing and
+// how many have been dead-lettered, for /health. Both are 0 if the queue
+// backend doesn't track them (e.g. ChannelQueue).
+func (p *IngestWorkerPool) BacklogStats(ctx context.Context) (processing int64, deadLettered int64) {
+	if pl, ok := p.queue.(interface {
+		ProcessingLen(ctx context.Context) (int64, error)
+	}); ok {
+		processing, _ = pl.ProcessingLen(ctx)
+	}
+	if dl, ok := p.queue.(interface {
+		DeadLetterLen(ctx context.Context) (int64, error)
+	}); ok {
+		deadLettered, _ = dl.DeadLetterLen(ctx)
+	}
+	return processing, deadLettered
+}
+
+func validateContext(c *Context) error {
+	if c.Category == "" {
+		return fmt.Errorf("category is required")
+	}
+	if c.Content == "" {
+		return fmt.Errorf("content is required")
+	}
+	if c.Source == "" {
+		return fmt.Errorf("source is required")
+	}
+	return nil
+}
+
+// validateContextPatch rejects a patch that would clear a required field
+// via an explicit empty string; a field the caller omitted entirely is
+// left as nil and passed straight through as a COALESCE no-op.
+func validateContextPatch(p *contextPatch) error {
+	if p.Category != nil && *p.Category == "" {
+		return fmt.Errorf("category cannot be empty")
+	}
+	if p.Content != nil && *p.Content == "" {
+		return fmt.Errorf("content cannot be empty")
+	}
+	if p.Source != nil && *p.Source == "" {
+		return fmt.Errorf("source cannot be empty")
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import "testing"
+
+func TestValidateContext(t *testing.T) {
+	valid := Context{Category: "notes", Content: "hello", Source: "api"}
+	if err := validateContext(&valid); err != nil {
+		t.Errorf("expected valid context to pass, got: %v", err)
+	}
+
+	cases := []struct {
+		name string
+		c    Context
+	}{
+		{"missing category", Context{Content: "hello", Source: "api"}},
+		{"missing content", Context{Category: "notes", Source: "api"}},
+		{"missing source", Context{Category: "notes", Content: "hello"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if err := validateContext(&c.c); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestValidateContextPatch(t *testing.T) {
+	empty := ""
+	set := "value"
+
+	if err := validateContextPatch(&contextPatch{}); err != nil {
+		t.Errorf("expected an all-nil patch to pass, got: %v", err)
+	}
+
+	if err := validateContextPatch(&contextPatch{Category: &set}); err != nil {
+		t.Errorf("expected a non-empty field to pass, got: %v", err)
+	}
+
+	cases := []struct {
+		name  string
+		patch contextPatch
+	}{
+		{"empty category", contextPatch{Category: &empty}},
+		{"empty content", contextPatch{Content: &empty}},
+		{"empty source", contextPatch{Source: &empty}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if err := validateContextPatch(&c.patch); err == nil {
+				t.Error("expected error for explicit empty string, got nil")
+			}
+		})
+	}
+}
+
+func TestIngestMaxAttemptsDefault(t *testing.T) {
+	if got := ingestMaxAttempts(); got != 5 {
+		t.Errorf("ingestMaxAttempts() = %d, want default of 5", got)
+	}
+}
@@ -26,6 +26,7 @@ type Context struct {
 	IsActive    bool      `json:"is_active"`
 	Priority    int       `json:"priority"`
 	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	Rank        float64   `json:"rank,omitempty"`
 }
 
 // ContextQuery represents query parameters
@@ -45,38 +46,91 @@ type ContextResponse struct {
 
 // HealthResponse represents health check response
 type HealthResponse struct {
-	Status          string `json:"status"`
-	Service         string `json:"service"`
-	DBConnected     bool   `json:"db_connected"`
-	RedisConnected  bool   `json:"redis_connected"`
+	Status           string  `json:"status"`
+	Service          string  `json:"service"`
+	DBConnected      bool    `json:"db_connected"`
+	RedisConnected   bool    `json:"redis_connected"`
+	QueueDepth       int64   `json:"queue_depth,omitempty"`
+	WorkerLagSeconds float64 `json:"worker_lag_seconds,omitempty"`
+	ProcessingDepth  int64   `json:"processing_depth,omitempty"`
+	DeadLetterDepth  int64   `json:"dead_letter_depth,omitempty"`
+
+	RollingViewRefreshedAt *time.Time `json:"rolling_view_refreshed_at,omitempty"`
+	RollingViewRefreshMS   float64    `json:"rolling_view_refresh_ms,omitempty"`
 }
 
-var (
-	db          *sql.DB
-	redisClient *redis.Client
-	ctx         = context.Background()
-)
+// App holds the dependencies handlers need. A single instance is built in
+// main and injected into every handler (as a method receiver) instead of
+// each handler reaching for package globals - this is what lets us wire
+// in new long-lived dependencies (ingestion queue, embedding client, ...)
+// later without touching every handler signature again.
+type App struct {
+	db    *sql.DB
+	redis redis.UniversalClient
+
+	searchCaps searchCapabilities
+	queue      Queue
+	ingest     *IngestWorkerPool
+	rolling    *RollingRefresher
+}
+
+var ctx = context.Background()
 
 func main() {
 	// Initialize database
-	if err := initDB(); err != nil {
+	db, err := initDB()
+	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
 	defer db.Close()
 
-	// Initialize Redis
-	initRedis()
+	// Initialize Redis (single node, Sentinel, or Cluster depending on config)
+	redisClient, err := newRedisClient()
+	if err != nil {
+		log.Printf("Warning: Could not configure Redis: %v", err)
+	} else if err := redisClient.Ping(ctx).Err(); err != nil {
+		log.Printf("Warning: Could not connect to Redis: %v", err)
+		redisClient = nil
+	} else {
+		log.Println("Connected to Redis")
+	}
+
+	// Recover any ingestion items that were in-flight when a previous
+	// instance crashed before workers start popping new ones.
+	queue := newIngestQueue(redisClient)
+	if rq, ok := queue.(*RedisQueue); ok {
+		if n, err := rq.RecoverProcessing(ctx); err != nil {
+			log.Printf("Warning: failed to recover in-flight ingestion items: %v", err)
+		} else if n > 0 {
+			log.Printf("Recovered %d in-flight ingestion items", n)
+		}
+	}
+
+	app := &App{db: db, redis: redisClient, searchCaps: detectSearchCapabilities(db), queue: queue}
+
+	app.ingest = NewIngestWorkerPool(app, queue, getIntEnv("INGEST_WORKERS", 2))
+	app.ingest.Start(ctx)
+
+	// rolling must be assigned before the invalidation subscriber starts,
+	// since that goroutine reads a.rolling on every message it handles.
+	app.rolling = NewRollingRefresher(app, getDurationEnv("ROLLING_REFRESH_INTERVAL", 60*time.Second))
+	app.rolling.Start(ctx)
+	app.startInvalidationSubscriber(ctx)
 
 	// Create router
 	r := mux.NewRouter()
 
 	// Routes
 	r.HandleFunc("/", rootHandler).Methods("GET")
-	r.HandleFunc("/health", healthHandler).Methods("GET")
-	r.HandleFunc("/context", getContextHandler).Methods("GET")
-	r.HandleFunc("/context/recent", getRecentContextHandler).Methods("GET")
-	r.HandleFunc("/context/rolling", getRollingContextHandler).Methods("GET")
-	r.HandleFunc("/context/search", searchContextHandler).Methods("POST")
+	r.HandleFunc("/health", app.healthHandler).Methods("GET")
+	r.HandleFunc("/context", app.getContextHandler).Methods("GET")
+	r.HandleFunc("/context", app.createContextHandler).Methods("POST")
+	r.HandleFunc("/context/batch", app.batchContextHandler).Methods("POST")
+	r.HandleFunc("/context/{id:[0-9]+}", app.updateContextHandler).Methods("PATCH")
+	r.HandleFunc("/context/{id:[0-9]+}", app.deleteContextHandler).Methods("DELETE")
+	r.HandleFunc("/context/recent", app.getRecentContextHandler).Methods("GET")
+	r.HandleFunc("/context/rolling", app.getRollingContextHandler).Methods("GET")
+	r.HandleFunc("/context/search", app.searchContextHandler).Methods("POST")
 
 	// Enable CORS
 	r.Use(corsMiddleware)
@@ -89,9 +143,9 @@ func main() {
 	}
 }
 
-func initDB() error {
+func initDB() (*sql.DB, error) {
 	dbType := getEnv("DB_TYPE", "postgres")
-	
+
 	var connStr string
 	if dbType == "postgres" {
 		host := getEnv("POSTGRES_HOST", "postgres")
@@ -99,22 +153,21 @@ func initDB() error {
 		user := getEnv("POSTGRES_USER", "aicos")
 		password := getEnv("POSTGRES_PASSWORD", "")
 		dbname := getEnv("POSTGRES_DB", "ai_chief_of_staff")
-		
+
 		connStr = fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
 			host, port, user, password, dbname)
 	} else {
-		return fmt.Errorf("unsupported DB_TYPE: %s (Go service requires PostgreSQL)", dbType)
+		return nil, fmt.Errorf("unsupported DB_TYPE: %s (Go service requires PostgreSQL)", dbType)
 	}
 
-	var err error
-	db, err = sql.Open("postgres", connStr)
+	db, err := sql.Open("postgres", connStr)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Test connection
 	if err = db.Ping(); err != nil {
-		return err
+		return nil, err
 	}
 
 	// Set connection pool settings
@@ -123,26 +176,7 @@ func initDB() error {
 	db.SetConnMaxLifetime(5 * time.Minute)
 
 	log.Println("Connected to PostgreSQL database")
-	return nil
-}
-
-func initRedis() {
-	redisURL := getEnv("REDIS_URL", "redis://redis:6379")
-	opt, err := redis.ParseURL(redisURL)
-	if err != nil {
-		log.Printf("Warning: Could not parse Redis URL: %v", err)
-		return
-	}
-
-	redisClient = redis.NewClient(opt)
-
-	// Test connection
-	if err := redisClient.Ping(ctx).Err(); err != nil {
-		log.Printf("Warning: Could not connect to Redis: %v", err)
-		redisClient = nil
-	} else {
-		log.Println("Connected to Redis")
-	}
+	return db, nil
 }
 
 func rootHandler(w http.ResponseWriter, r *http.Request) {
@@ -154,20 +188,37 @@ func rootHandler(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, response)
 }
 
-func healthHandler(w http.ResponseWriter, r *http.Request) {
+func (a *App) healthHandler(w http.ResponseWriter, r *http.Request) {
 	health := HealthResponse{
 		Status:         "healthy",
 		Service:        "context-service",
-		DBConnected:    db != nil && db.Ping() == nil,
+		DBConnected:    a.db != nil && a.db.Ping() == nil,
 		RedisConnected: false,
 	}
 
-	if redisClient != nil {
-		if err := redisClient.Ping(ctx).Err(); err == nil {
+	if a.redis != nil {
+		if err := a.redis.Ping(ctx).Err(); err == nil {
 			health.RedisConnected = true
 		}
 	}
 
+	if a.ingest != nil {
+		depth, lag := a.ingest.Stats(ctx)
+		health.QueueDepth = depth
+		health.WorkerLagSeconds = lag.Seconds()
+
+		processing, deadLettered := a.ingest.BacklogStats(ctx)
+		health.ProcessingDepth = processing
+		health.DeadLetterDepth = deadLettered
+	}
+
+	if a.rolling != nil {
+		if refreshedAt, duration := a.rolling.Stats(); !refreshedAt.IsZero() {
+			health.RollingViewRefreshedAt = &refreshedAt
+			health.RollingViewRefreshMS = float64(duration.Microseconds()) / 1000
+		}
+	}
+
 	if !health.DBConnected {
 		health.Status = "unhealthy"
 		respondJSON(w, http.StatusServiceUnavailable, health)
@@ -177,7 +228,7 @@ func healthHandler(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, health)
 }
 
-func getContextHandler(w http.ResponseWriter, r *http.Request) {
+func (a *App) getContextHandler(w http.ResponseWriter, r *http.Request) {
 	// Parse query parameters
 	query := ContextQuery{
 		Category:   r.URL.Query().Get("category"),
@@ -186,17 +237,20 @@ func getContextHandler(w http.ResponseWriter, r *http.Request) {
 		ActiveOnly: getBoolParam(r, "active_only", true),
 	}
 
-	// Check cache
 	cacheKey := fmt.Sprintf("context:%s:%s:%d:%v", query.Category, query.Source, query.Limit, query.ActiveOnly)
-	if cached, found := getFromCache(cacheKey); found {
-		var response ContextResponse
-		if err := json.Unmarshal([]byte(cached), &response); err == nil {
-			response.Cached = true
-			respondJSON(w, http.StatusOK, response)
-			return
-		}
+
+	response, err := a.fetchWithCache(cacheKey, cacheHardTTL(), func() (ContextResponse, error) {
+		return a.queryContext(query)
+	})
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Database query failed: "+err.Error())
+		return
 	}
 
+	respondJSON(w, http.StatusOK, response)
+}
+
+func (a *App) queryContext(query ContextQuery) (ContextResponse, error) {
 	// Build SQL query
 	sqlQuery := "SELECT id, category, content, source, created_at, is_active, priority, expires_at FROM context WHERE 1=1"
 	args := []interface{}{}
@@ -223,10 +277,9 @@ func getContextHandler(w http.ResponseWriter, r *http.Request) {
 	args = append(args, query.Limit)
 
 	// Execute query
-	rows, err := db.Query(sqlQuery, args...)
+	rows, err := a.db.Query(sqlQuery, args...)
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, "Database query failed: "+err.Error())
-		return
+		return ContextResponse{}, err
 	}
 	defer rows.Close()
 
@@ -241,57 +294,48 @@ func getContextHandler(w http.ResponseWriter, r *http.Request) {
 		contexts = append(contexts, c)
 	}
 
-	response := ContextResponse{
-		Contexts: contexts,
-		Count:    len(contexts),
-		Cached:   false,
-	}
-
-	// Cache result
-	if jsonData, err := json.Marshal(response); err == nil {
-		setCache(cacheKey, string(jsonData), 5*time.Minute)
-	}
-
-	respondJSON(w, http.StatusOK, response)
+	return ContextResponse{Contexts: contexts, Count: len(contexts)}, nil
 }
 
-func getRecentContextHandler(w http.ResponseWriter, r *http.Request) {
+func (a *App) getRecentContextHandler(w http.ResponseWriter, r *http.Request) {
 	days := getIntParam(r, "days", 14)
 	category := r.URL.Query().Get("category")
 
-	// Check cache
 	cacheKey := fmt.Sprintf("recent_context:%d:%s", days, category)
-	if cached, found := getFromCache(cacheKey); found {
-		var response ContextResponse
-		if err := json.Unmarshal([]byte(cached), &response); err == nil {
-			response.Cached = true
-			respondJSON(w, http.StatusOK, response)
-			return
-		}
+
+	response, err := a.fetchWithCache(cacheKey, cacheHardTTL(), func() (ContextResponse, error) {
+		return a.queryRecentContext(days, category)
+	})
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Database query failed: "+err.Error())
+		return
 	}
 
+	respondJSON(w, http.StatusOK, response)
+}
+
+func (a *App) queryRecentContext(days int, category string) (ContextResponse, error) {
 	// Build query
 	sqlQuery := `
-		SELECT id, category, content, source, created_at, is_active, priority, expires_at 
-		FROM context 
-		WHERE is_active = true 
+		SELECT id, category, content, source, created_at, is_active, priority, expires_at
+		FROM context
+		WHERE is_active = true
 		AND created_at >= NOW() - INTERVAL '%d days'
 	`
 	args := []interface{}{}
-	
+
 	if category != "" {
 		sqlQuery += " AND category = $1"
 		args = append(args, category)
 	}
-	
+
 	sqlQuery += " ORDER BY created_at DESC LIMIT 200"
 	sqlQuery = fmt.Sprintf(sqlQuery, days)
 
 	// Execute
-	rows, err := db.Query(sqlQuery, args...)
+	rows, err := a.db.Query(sqlQuery, args...)
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, "Database query failed: "+err.Error())
-		return
+		return ContextResponse{}, err
 	}
 	defer rows.Close()
 
@@ -305,36 +349,81 @@ func getRecentContextHandler(w http.ResponseWriter, r *http.Request) {
 		contexts = append(contexts, c)
 	}
 
-	response := ContextResponse{
-		Contexts: contexts,
-		Count:    len(contexts),
-		Cached:   false,
+	return ContextResponse{Contexts: contexts, Count: len(contexts)}, nil
+}
+
+// rollingCacheKey is where the full rolling-window JSON response is
+// memoized, on top of the context_rolling_2w materialized view, so the
+// typical request is a single Redis GET. Its TTL tracks
+// ROLLING_REFRESH_INTERVAL: there's no point serving a cached response
+// older than the view itself.
+const rollingCacheKey = "rolling:v2w"
+
+func (a *App) getRollingContextHandler(w http.ResponseWriter, r *http.Request) {
+	if getBoolParam(r, "fresh", false) {
+		response, err := a.queryRollingContextLive()
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "Database query failed: "+err.Error())
+			return
+		}
+		respondJSON(w, http.StatusOK, response)
+		return
 	}
 
-	// Cache
-	if jsonData, err := json.Marshal(response); err == nil {
-		setCache(cacheKey, string(jsonData), 10*time.Minute)
+	response, err := a.fetchWithCache(rollingCacheKey, a.rolling.interval, a.queryRollingContext)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Database query failed: "+err.Error())
+		return
 	}
 
 	respondJSON(w, http.StatusOK, response)
 }
 
-func getRollingContextHandler(w http.ResponseWriter, r *http.Request) {
-	// Get rolling 2-week context window
+// queryRollingContext reads the materialized view a background refresher
+// keeps current - see rolling.go.
+func (a *App) queryRollingContext() (ContextResponse, error) {
 	sqlQuery := `
-		SELECT id, category, content, source, created_at, is_active, priority, expires_at 
-		FROM context 
-		WHERE is_active = true 
+		SELECT id, category, content, source, created_at, is_active, priority, expires_at
+		FROM context_rolling_2w
+		ORDER BY priority DESC, created_at DESC
+	`
+
+	rows, err := a.db.Query(sqlQuery)
+	if err != nil {
+		return ContextResponse{}, err
+	}
+	defer rows.Close()
+
+	contexts := []Context{}
+	for rows.Next() {
+		var c Context
+		if err := rows.Scan(&c.ID, &c.Category, &c.Content, &c.Source, &c.CreatedAt, &c.IsActive, &c.Priority, &c.ExpiresAt); err != nil {
+			continue
+		}
+		contexts = append(contexts, c)
+	}
+
+	return ContextResponse{Contexts: contexts, Count: len(contexts)}, nil
+}
+
+// queryRollingContextLive runs the rolling window query directly against
+// context, bypassing both the materialized view and the Redis memoization
+// of it. It backs the ?fresh=1 escape hatch used to debug staleness in
+// either cache.
+func (a *App) queryRollingContextLive() (ContextResponse, error) {
+	sqlQuery := `
+		SELECT id, category, content, source, created_at, is_active, priority, expires_at
+		FROM context
+		WHERE is_active = true
 		AND created_at >= NOW() - INTERVAL '14 days'
 		AND (expires_at IS NULL OR expires_at > NOW())
-		ORDER BY priority DESC, created_at DESC 
+		ORDER BY priority DESC, created_at DESC
 		LIMIT 500
 	`
 
-	rows, err := db.Query(sqlQuery)
+	rows, err := a.db.Query(sqlQuery)
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, "Database query failed: "+err.Error())
-		return
+		return ContextResponse{}, err
 	}
 	defer rows.Close()
 
@@ -347,20 +436,16 @@ func getRollingContextHandler(w http.ResponseWriter, r *http.Request) {
 		contexts = append(contexts, c)
 	}
 
-	response := ContextResponse{
-		Contexts: contexts,
-		Count:    len(contexts),
-		Cached:   false,
-	}
-
-	respondJSON(w, http.StatusOK, response)
+	return ContextResponse{Contexts: contexts, Count: len(contexts)}, nil
 }
 
-func searchContextHandler(w http.ResponseWriter, r *http.Request) {
+func (a *App) searchContextHandler(w http.ResponseWriter, r *http.Request) {
 	var searchReq struct {
-		Query    string `json:"query"`
-		Category string `json:"category"`
-		Limit    int    `json:"limit"`
+		Query     string    `json:"query"`
+		Category  string    `json:"category"`
+		Limit     int       `json:"limit"`
+		Mode      string    `json:"mode"`
+		Embedding []float64 `json:"embedding"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&searchReq); err != nil {
@@ -372,26 +457,21 @@ func searchContextHandler(w http.ResponseWriter, r *http.Request) {
 		searchReq.Limit = 50
 	}
 
-	// Simple text search (can be enhanced with full-text search)
-	sqlQuery := `
-		SELECT id, category, content, source, created_at, is_active, priority, expires_at 
-		FROM context 
-		WHERE is_active = true 
-		AND content ILIKE $1
-	`
-	args := []interface{}{"%" + searchReq.Query + "%"}
-	argPos := 2
+	mode := resolveSearchMode(searchReq.Mode, a.searchCaps)
 
-	if searchReq.Category != "" {
-		sqlQuery += fmt.Sprintf(" AND category = $%d", argPos)
-		args = append(args, searchReq.Category)
-		argPos++
+	var embedding []float64
+	if mode == searchModeSemantic || mode == searchModeHybrid {
+		var err error
+		embedding, err = a.resolveEmbedding(searchReq.Embedding, searchReq.Query)
+		if err != nil {
+			respondError(w, http.StatusBadGateway, "Failed to obtain query embedding: "+err.Error())
+			return
+		}
 	}
 
-	sqlQuery += fmt.Sprintf(" ORDER BY priority DESC, created_at DESC LIMIT $%d", argPos)
-	args = append(args, searchReq.Limit)
+	sqlQuery, args := buildSearchQuery(mode, searchReq.Query, searchReq.Category, embedding, searchReq.Limit)
 
-	rows, err := db.Query(sqlQuery, args...)
+	rows, err := a.db.Query(sqlQuery, args...)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "Search failed: "+err.Error())
 		return
@@ -401,7 +481,13 @@ func searchContextHandler(w http.ResponseWriter, r *http.Request) {
 	contexts := []Context{}
 	for rows.Next() {
 		var c Context
-		if err := rows.Scan(&c.ID, &c.Category, &c.Content, &c.Source, &c.CreatedAt, &c.IsActive, &c.Priority, &c.ExpiresAt); err != nil {
+		var scanErr error
+		if mode == searchModeILIKE {
+			scanErr = rows.Scan(&c.ID, &c.Category, &c.Content, &c.Source, &c.CreatedAt, &c.IsActive, &c.Priority, &c.ExpiresAt)
+		} else {
+			scanErr = rows.Scan(&c.ID, &c.Category, &c.Content, &c.Source, &c.CreatedAt, &c.IsActive, &c.Priority, &c.ExpiresAt, &c.Rank)
+		}
+		if scanErr != nil {
 			continue
 		}
 		contexts = append(contexts, c)
@@ -418,32 +504,34 @@ func searchContextHandler(w http.ResponseWriter, r *http.Request) {
 
 // Helper functions
 
-func getFromCache(key string) (string, bool) {
-	if redisClient == nil {
-		return "", false
-	}
+// invalidationChannel carries messages whenever a context row changes, so
+// every instance's cache can drop the keys it could have affected.
+const invalidationChannel = "context:invalidate"
 
-	val, err := redisClient.Get(ctx, key).Result()
-	if err != nil {
-		return "", false
-	}
-
-	return val, true
+type invalidationMessage struct {
+	Category string `json:"category"`
+	Source   string `json:"source"`
 }
 
-func setCache(key string, value string, ttl time.Duration) {
-	if redisClient == nil {
+func (a *App) publishInvalidation(ctx context.Context, category, source string) {
+	if a.redis == nil {
+		return
+	}
+
+	data, err := json.Marshal(invalidationMessage{Category: category, Source: source})
+	if err != nil {
+		log.Printf("Failed to encode invalidation message: %v", err)
 		return
 	}
 
-	if err := redisClient.Set(ctx, key, value, ttl).Err(); err != nil {
-		log.Printf("Cache set error: %v", err)
+	if err := a.redis.Publish(ctx, invalidationChannel, data).Err(); err != nil {
+		log.Printf("Failed to publish invalidation message: %v", err)
 	}
 }
 
 func respondJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteStatus(status)
+	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(data)
 }
 
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// QueueItem is a unit of ingestion work: a context row waiting to be
+// validated and written to Postgres.
+type QueueItem struct {
+	Op         string        `json:"op"` // "insert", "update", "delete"
+	ID         int           `json:"id,omitempty"`
+	Context    *Context      `json:"context,omitempty"` // "insert": the full row to write
+	Patch      *contextPatch `json:"patch,omitempty"`   // "update": only the fields to change
+	EnqueuedAt time.Time     `json:"enqueued_at"`
+	Attempts   int           `json:"attempts,omitempty"`
+
+	// raw is the exact bytes this item was popped as, so RedisQueue.Ack
+	// can LREM the matching list entry without relying on json.Marshal
+	// producing byte-identical output on re-encode.
+	raw string
+}
+
+// contextPatch is a partial update to a context row: every field is
+// optional, so a caller can PATCH just {"priority": 5} and leave the
+// rest alone. It's applied as a COALESCE update in the worker rather
+// than merged against a snapshot in the handler, so two concurrent
+// patches to the same row never clobber each other.
+type contextPatch struct {
+	Category  *string    `json:"category"`
+	Content   *string    `json:"content"`
+	Source    *string    `json:"source"`
+	Priority  *int       `json:"priority"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// Queue is the ingestion queue contract. It's implemented by an
+// in-process channel queue (dev) and a Redis-backed list queue (prod), so
+// the write handlers and worker pool don't care which is in use.
+type Queue interface {
+	Push(ctx context.Context, item QueueItem) error
+	Pop(ctx context.Context) (QueueItem, error)
+	Len(ctx context.Context) (int64, error)
+}
+
+// newIngestQueue picks the queue backend: Redis when INGEST_QUEUE_BACKEND
+// is "redis" and a Redis client is available, otherwise the in-process
+// channel queue.
+func newIngestQueue(redisClient redis.UniversalClient) Queue {
+	backend := strings.ToLower(getEnv("INGEST_QUEUE_BACKEND", "channel"))
+	if backend == "redis" && redisClient != nil {
+		return NewRedisQueue(redisClient)
+	}
+	return NewChannelQueue(1000)
+}
+
+// ChannelQueue is an in-process, non-durable queue for local development;
+// items are lost on restart.
+type ChannelQueue struct {
+	items chan QueueItem
+}
+
+func NewChannelQueue(size int) *ChannelQueue {
+	return &ChannelQueue{items: make(chan QueueItem, size)}
+}
+
+func (q *ChannelQueue) Push(ctx context.Context, item QueueItem) error {
+	select {
+	case q.items <- item:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q *ChannelQueue) Pop(ctx context.Context) (QueueItem, error) {
+	select {
+	case item := <-q.items:
+		return item, nil
+	case <-ctx.Done():
+		return QueueItem{}, ctx.Err()
+	}
+}
+
+func (q *ChannelQueue) Len(ctx context.Context) (int64, error) {
+	return int64(len(q.items)), nil
+}
+
+// The {ingest} hash tag pins all three keys to the same Redis Cluster slot.
+// BRPopLPush/RPopLPush move items between the main and processing lists
+// atomically, which Redis
+// Cluster only allows for keys that hash to the same slot - without the
+// tag these would land on different shards almost every time and every
+// call would fail with CROSSSLOT.
+const (
+	redisQueueMainKey       = "{ingest}:queue"
+	redisQueueProcessingKey = "{ingest}:processing"
+	redisQueueDeadLetterKey = "{ingest}:deadletter"
+)
+
+// RedisQueue is a durable, at-least-once ingestion queue backed by a
+// Redis list. Pop moves an item atomically from the main list onto a
+// processing list (BRPOPLPUSH); a worker Acks once the item is durably
+// written to Postgres, removing it from the processing list. If a worker
+// crashes first, the item stays on the processing list until
+// RecoverProcessing puts it back on the main list at next startup. An
+// item that keeps failing past IngestWorkerPool's attempt limit is moved
+// to a dead-letter list instead of being retried forever.
+type RedisQueue struct {
+	client redis.UniversalClient
+}
+
+func NewRedisQueue(client redis.UniversalClient) *RedisQueue {
+	return &RedisQueue{client: client}
+}
+
+func (q *RedisQueue) Push(ctx context.Context, item QueueItem) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	return q.client.LPush(ctx, redisQueueMainKey, data).Err()
+}
+
+func (q *RedisQueue) Pop(ctx context.Context) (QueueItem, error) {
+	data, err := q.client.BRPopLPush(ctx, redisQueueMainKey, redisQueueProcessingKey, 0).Result()
+	if err != nil {
+		return QueueItem{}, err
+	}
+
+	var item QueueItem
+	if err := json.Unmarshal([]byte(data), &item); err != nil {
+		return QueueItem{}, fmt.Errorf("decoding queue item: %w", err)
+	}
+	item.raw = data
+
+	return item, nil
+}
+
+// Ack removes a successfully processed item from the processing list.
+func (q *RedisQueue) Ack(ctx context.Context, item QueueItem) error {
+	return q.removeFromProcessing(ctx, item)
+}
+
+// Retry persists item's incremented attempt count back onto the
+// processing list, replacing the entry it was popped as. Without this a
+// failing item's attempts reset to whatever was marshaled at Push time
+// every time RecoverProcessing re-delivers it after a crash, so it could
+// never reach ingestMaxAttempts and get dead-lettered.
+func (q *RedisQueue) Retry(ctx context.Context, item QueueItem) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	oldRaw := item.raw
+	_, err = q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if oldRaw != "" {
+			pipe.LRem(ctx, redisQueueProcessingKey, 1, oldRaw)
+		}
+		pipe.LPush(ctx, redisQueueProcessingKey, data)
+		return nil
+	})
+	return err
+}
+
+// DeadLetter moves an item that has exhausted its retry attempts onto
+// the dead-letter list and off the processing list, so it stops being
+// recovered and retried forever on every restart.
+func (q *RedisQueue) DeadLetter(ctx context.Context, item QueueItem) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	if err := q.client.LPush(ctx, redisQueueDeadLetterKey, data).Err(); err != nil {
+		return err
+	}
+	return q.removeFromProcessing(ctx, item)
+}
+
+func (q *RedisQueue) removeFromProcessing(ctx context.Context, item QueueItem) error {
+	data := item.raw
+	if data == "" {
+		marshaled, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		data = string(marshaled)
+	}
+	return q.client.LRem(ctx, redisQueueProcessingKey, 1, data).Err()
+}
+
+func (q *RedisQueue) Len(ctx context.Context) (int64, error) {
+	return q.client.LLen(ctx, redisQueueMainKey).Result()
+}
+
+// ProcessingLen reports how many items are currently checked out for
+// processing, for /health.
+func (q *RedisQueue) ProcessingLen(ctx context.Context) (int64, error) {
+	return q.client.LLen(ctx, redisQueueProcessingKey).Result()
+}
+
+// DeadLetterLen reports how many items have exhausted their retry
+// attempts, for /health.
+func (q *RedisQueue) DeadLetterLen(ctx context.Context) (int64, error) {
+	return q.client.LLen(ctx, redisQueueDeadLetterKey).Result()
+}
+
+// RecoverProcessing drains the processing list back onto the main queue.
+// Call it once at startup, before workers start popping, so items that
+// were in-flight when a previous instance crashed aren't lost.
+func (q *RedisQueue) RecoverProcessing(ctx context.Context) (int, error) {
+	var recovered int
+	for {
+		_, err := q.client.RPopLPush(ctx, redisQueueProcessingKey, redisQueueMainKey).Result()
+		if err == redis.Nil {
+			break
+		}
+		if err != nil {
+			return recovered, err
+		}
+		recovered++
+	}
+	return recovered, nil
+}
+
+func getIntEnv(key string, defaultValue int) int {
+	if value := getEnv(key, ""); value != "" {
+		if intValue, err := strconv.Atoi(value); err == nil {
+			return intValue
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisMode selects which Redis deployment topology to connect to.
+type RedisMode string
+
+const (
+	RedisModeSingle   RedisMode = "single"
+	RedisModeSentinel RedisMode = "sentinel"
+	RedisModeCluster  RedisMode = "cluster"
+)
+
+// redisConfig holds the resolved connection parameters for whichever
+// Redis topology is in use, derived from either a scheme-style REDIS_URL
+// (redis://, redis+sentinel://, redis+cluster://) or discrete env vars.
+type redisConfig struct {
+	Mode             RedisMode
+	Addrs            []string
+	MasterName       string
+	Username         string
+	Password         string
+	SentinelPassword string
+	DB               int
+}
+
+// newRedisClient builds a redis.UniversalClient for the configured
+// topology. Handlers talk to this interface rather than *redis.Client so
+// getFromCache/setCache (and the pub/sub subscriber added later) don't
+// need to know whether they're hitting a single node, a Sentinel-backed
+// master/replica set, or a Cluster.
+func newRedisClient() (redis.UniversalClient, error) {
+	cfg, err := loadRedisConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	switch cfg.Mode {
+	case RedisModeSentinel:
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       cfg.MasterName,
+			SentinelAddrs:    cfg.Addrs,
+			SentinelPassword: cfg.SentinelPassword,
+			Username:         cfg.Username,
+			Password:         cfg.Password,
+			DB:               cfg.DB,
+		}), nil
+	case RedisModeCluster:
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:    cfg.Addrs,
+			Username: cfg.Username,
+			Password: cfg.Password,
+		}), nil
+	default:
+		return redis.NewClient(&redis.Options{
+			Addr:     cfg.Addrs[0],
+			Username: cfg.Username,
+			Password: cfg.Password,
+			DB:       cfg.DB,
+		}), nil
+	}
+}
+
+// loadRedisConfig resolves the Redis topology. REDIS_MODE ("single",
+// "sentinel", "cluster") wins when set; otherwise the scheme of REDIS_URL
+// (redis+sentinel://, redis+cluster://, plain redis://) decides. Sentinel
+// and Cluster addresses can come from that URL or from the discrete
+// REDIS_SENTINEL_ADDRS/REDIS_MASTER_NAME/REDIS_CLUSTER_ADDRS env vars.
+func loadRedisConfig() (redisConfig, error) {
+	mode := RedisMode(strings.ToLower(getEnv("REDIS_MODE", "")))
+	redisURL := getEnv("REDIS_URL", "")
+
+	if mode == "" {
+		switch {
+		case strings.HasPrefix(redisURL, "redis+sentinel://"):
+			mode = RedisModeSentinel
+		case strings.HasPrefix(redisURL, "redis+cluster://"):
+			mode = RedisModeCluster
+		default:
+			mode = RedisModeSingle
+		}
+	}
+
+	switch mode {
+	case RedisModeSentinel:
+		if strings.HasPrefix(redisURL, "redis+sentinel://") {
+			return parseSentinelURL(redisURL)
+		}
+		return sentinelConfigFromEnv()
+	case RedisModeCluster:
+		if strings.HasPrefix(redisURL, "redis+cluster://") {
+			return parseClusterURL(redisURL)
+		}
+		return clusterConfigFromEnv()
+	default:
+		if redisURL == "" {
+			redisURL = "redis://redis:6379"
+		}
+		opt, err := redis.ParseURL(redisURL)
+		if err != nil {
+			return redisConfig{}, fmt.Errorf("parsing REDIS_URL: %w", err)
+		}
+		return redisConfig{
+			Mode:     RedisModeSingle,
+			Addrs:    []string{opt.Addr},
+			Username: opt.Username,
+			Password: opt.Password,
+			DB:       opt.DB,
+		}, nil
+	}
+}
+
+// parseSentinelURL parses redis+sentinel://[user:pass@]host1,host2/mymaster[/db].
+func parseSentinelURL(raw string) (redisConfig, error) {
+	userinfo, hostsAndPath := splitUserinfo(strings.TrimPrefix(raw, "redis+sentinel://"))
+
+	parts := strings.SplitN(hostsAndPath, "/", 3)
+	if len(parts) < 2 || parts[1] == "" {
+		return redisConfig{}, fmt.Errorf("redis+sentinel:// URL must include a master name, e.g. redis+sentinel://host1,host2/mymaster")
+	}
+
+	cfg := redisConfig{
+		Mode:             RedisModeSentinel,
+		Addrs:            strings.Split(parts[0], ","),
+		MasterName:       parts[1],
+		SentinelPassword: getEnv("REDIS_SENTINEL_PASSWORD", ""),
+	}
+	if userinfo != "" {
+		cfg.Username, cfg.Password = splitCreds(userinfo)
+	}
+	if len(parts) == 3 && parts[2] != "" {
+		db, err := strconv.Atoi(parts[2])
+		if err != nil {
+			return redisConfig{}, fmt.Errorf("invalid db index %q in REDIS_URL: %w", parts[2], err)
+		}
+		cfg.DB = db
+	}
+
+	return cfg, nil
+}
+
+// parseClusterURL parses redis+cluster://[user:pass@]host1,host2[,host3].
+func parseClusterURL(raw string) (redisConfig, error) {
+	userinfo, hostsAndPath := splitUserinfo(strings.TrimPrefix(raw, "redis+cluster://"))
+	hosts := strings.SplitN(hostsAndPath, "/", 2)[0]
+	if hosts == "" {
+		return redisConfig{}, fmt.Errorf("redis+cluster:// URL must include at least one host")
+	}
+
+	cfg := redisConfig{
+		Mode:  RedisModeCluster,
+		Addrs: strings.Split(hosts, ","),
+	}
+	if userinfo != "" {
+		cfg.Username, cfg.Password = splitCreds(userinfo)
+	}
+
+	return cfg, nil
+}
+
+func sentinelConfigFromEnv() (redisConfig, error) {
+	addrs := getEnv("REDIS_SENTINEL_ADDRS", "")
+	master := getEnv("REDIS_MASTER_NAME", "")
+	if addrs == "" || master == "" {
+		return redisConfig{}, fmt.Errorf("REDIS_MODE=sentinel requires REDIS_SENTINEL_ADDRS and REDIS_MASTER_NAME")
+	}
+	return redisConfig{
+		Mode:             RedisModeSentinel,
+		Addrs:            strings.Split(addrs, ","),
+		MasterName:       master,
+		Password:         getEnv("REDIS_PASSWORD", ""),
+		SentinelPassword: getEnv("REDIS_SENTINEL_PASSWORD", ""),
+	}, nil
+}
+
+func clusterConfigFromEnv() (redisConfig, error) {
+	addrs := getEnv("REDIS_CLUSTER_ADDRS", "")
+	if addrs == "" {
+		return redisConfig{}, fmt.Errorf("REDIS_MODE=cluster requires REDIS_CLUSTER_ADDRS")
+	}
+	return redisConfig{
+		Mode:     RedisModeCluster,
+		Addrs:    strings.Split(addrs, ","),
+		Password: getEnv("REDIS_PASSWORD", ""),
+	}, nil
+}
+
+func splitUserinfo(s string) (userinfo, rest string) {
+	if i := strings.Index(s, "@"); i != -1 {
+		return s[:i], s[i+1:]
+	}
+	return "", s
+}
+
+func splitCreds(userinfo string) (user, pass string) {
+	if i := strings.Index(userinfo, ":"); i != -1 {
+		return userinfo[:i], userinfo[i+1:]
+	}
+	return userinfo, ""
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// RollingRefresher keeps the context_rolling_2w materialized view current
+// by periodically issuing REFRESH MATERIALIZED VIEW CONCURRENTLY, so
+// getRollingContextHandler's normal path is a view read plus a Redis GET
+// rather than the 14-day/top-500 query on every request.
+type RollingRefresher struct {
+	app      *App
+	interval time.Duration
+	trigger  chan struct{}
+
+	lastRefreshAt atomic.Value // time.Time
+	lastDuration  atomic.Value // time.Duration
+}
+
+func NewRollingRefresher(app *App, interval time.Duration) *RollingRefresher {
+	return &RollingRefresher{
+		app:      app,
+		interval: interval,
+		trigger:  make(chan struct{}, 1),
+	}
+}
+
+// Start launches the refresh loop. It runs until ctx is cancelled.
+func (rr *RollingRefresher) Start(ctx context.Context) {
+	go rr.run(ctx)
+}
+
+func (rr *RollingRefresher) run(ctx context.Context) {
+	rr.refresh(ctx)
+
+	ticker := time.NewTicker(rr.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			rr.refresh(ctx)
+		case <-rr.trigger:
+			rr.refresh(ctx)
+		}
+	}
+}
+
+// TriggerRefresh asks for an out-of-cycle refresh, e.g. on receipt of a
+// context:invalidate message. It never blocks: a refresh already pending
+// covers this request too.
+func (rr *RollingRefresher) TriggerRefresh() {
+	select {
+	case rr.trigger <- struct{}{}:
+	default:
+	}
+}
+
+func (rr *RollingRefresher) refresh(ctx context.Context) {
+	// The view is created WITH NO DATA, and Postgres rejects CONCURRENTLY
+	// against a never-populated matview, so the very first refresh has to
+	// be a plain (locking) one; every refresh after that can use
+	// CONCURRENTLY, which doesn't block readers.
+	stmt := "REFRESH MATERIALIZED VIEW CONCURRENTLY context_rolling_2w"
+	if _, ok := rr.lastRefreshAt.Load().(time.Time); !ok {
+		stmt = "REFRESH MATERIALIZED VIEW context_rolling_2w"
+	}
+
+	start := time.Now()
+	_, err := rr.app.db.ExecContext(ctx, stmt)
+	if err != nil {
+		log.Printf("rolling view refresh failed: %v", err)
+		return
+	}
+
+	rr.lastDuration.Store(time.Since(start))
+	rr.lastRefreshAt.Store(time.Now())
+
+	rr.app.deleteByPattern(ctx, rollingCacheKey)
+}
+
+// Stats reports when the view was last refreshed and how long that took,
+// for /health.
+func (rr *RollingRefresher) Stats() (lastRefreshAt time.Time, lastDuration time.Duration) {
+	if t, ok := rr.lastRefreshAt.Load().(time.Time); ok {
+		lastRefreshAt = t
+	}
+	if d, ok := rr.lastDuration.Load().(time.Duration); ok {
+		lastDuration = d
+	}
+	return lastRefreshAt, lastDuration
+}
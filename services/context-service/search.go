@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type searchMode string
+
+const (
+	searchModeILIKE    searchMode = "ilike"
+	searchModeText     searchMode = "text"
+	searchModeSemantic searchMode = "semantic"
+	searchModeHybrid   searchMode = "hybrid"
+)
+
+// searchCapabilities records which optional search features the connected
+// Postgres database actually supports, detected once at startup so every
+// request doesn't pay for a catalog lookup.
+type searchCapabilities struct {
+	fullText bool // context.tsv exists (migration 0001)
+	vector   bool // pgvector extension + context.embedding exist (migration 0002)
+}
+
+// detectSearchCapabilities inspects information_schema/pg_extension to see
+// which of the optional search migrations have been applied. Deployments
+// that haven't run them keep working via the ILIKE fallback.
+func detectSearchCapabilities(db *sql.DB) searchCapabilities {
+	var caps searchCapabilities
+
+	if db == nil {
+		return caps
+	}
+
+	if err := db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM information_schema.columns
+			WHERE table_name = 'context' AND column_name = 'tsv'
+		)
+	`).Scan(&caps.fullText); err != nil {
+		log.Printf("Could not detect full-text search support: %v", err)
+	}
+
+	if err := db.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'vector')
+		AND EXISTS (
+			SELECT 1 FROM information_schema.columns
+			WHERE table_name = 'context' AND column_name = 'embedding'
+		)
+	`).Scan(&caps.vector); err != nil {
+		log.Printf("Could not detect semantic search support: %v", err)
+	}
+
+	return caps
+}
+
+// resolveSearchMode maps the caller's requested mode onto what the
+// database can actually serve, falling back a step at a time: hybrid ->
+// semantic -> text -> ILIKE, semantic -> text -> ILIKE, text -> ILIKE. An
+// unspecified mode (the common case - most callers never send one) uses
+// the best capability available, same as "text" would, so full-text
+// ranking is the default the moment migration 0001 has run rather than
+// something callers have to opt into. A genuinely unrecognized non-empty
+// mode string is instead treated as an explicit request for the literal
+// ILIKE path.
+func resolveSearchMode(requested string, caps searchCapabilities) searchMode {
+	switch searchMode(strings.ToLower(requested)) {
+	case searchModeHybrid:
+		if caps.vector && caps.fullText {
+			return searchModeHybrid
+		}
+		if caps.vector {
+			return searchModeSemantic
+		}
+		if caps.fullText {
+			return searchModeText
+		}
+	case searchModeSemantic:
+		if caps.vector {
+			return searchModeSemantic
+		}
+		if caps.fullText {
+			return searchModeText
+		}
+	case "", searchModeText:
+		if caps.fullText {
+			return searchModeText
+		}
+	}
+
+	return searchModeILIKE
+}
+
+// buildSearchQuery builds the SQL and args for the resolved search mode.
+// Text, semantic and hybrid modes return a `rank` column so callers can see why a
+// result was ordered where it was; ILIKE keeps the old column set for
+// backward compatibility.
+func buildSearchQuery(mode searchMode, query, category string, embedding []float64, limit int) (string, []interface{}) {
+	switch mode {
+	case searchModeSemantic:
+		return buildSemanticQuery(query, category, embedding, limit)
+	case searchModeHybrid:
+		return buildHybridQuery(query, category, embedding, limit)
+	case searchModeText:
+		return buildTextQuery(query, category, limit)
+	default:
+		return buildILIKEQuery(query, category, limit)
+	}
+}
+
+func buildILIKEQuery(query, category string, limit int) (string, []interface{}) {
+	sqlQuery := `
+		SELECT id, category, content, source, created_at, is_active, priority, expires_at
+		FROM context
+		WHERE is_active = true
+		AND content ILIKE $1
+	`
+	args := []interface{}{"%" + query + "%"}
+	argPos := 2
+
+	if category != "" {
+		sqlQuery += fmt.Sprintf(" AND category = $%d", argPos)
+		args = append(args, category)
+		argPos++
+	}
+
+	sqlQuery += fmt.Sprintf(" ORDER BY priority DESC, created_at DESC LIMIT $%d", argPos)
+	args = append(args, limit)
+
+	return sqlQuery, args
+}
+
+func buildTextQuery(query, category string, limit int) (string, []interface{}) {
+	sqlQuery := `
+		SELECT id, category, content, source, created_at, is_active, priority, expires_at,
+			ts_rank_cd(tsv, websearch_to_tsquery('english', $1)) * log(1 + priority) AS rank
+		FROM context
+		WHERE is_active = true
+		AND tsv @@ websearch_to_tsquery('english', $1)
+	`
+	args := []interface{}{query}
+	argPos := 2
+
+	if category != "" {
+		sqlQuery += fmt.Sprintf(" AND category = $%d", argPos)
+		args = append(args, category)
+		argPos++
+	}
+
+	sqlQuery += fmt.Sprintf(" ORDER BY rank DESC, created_at DESC LIMIT $%d", argPos)
+	args = append(args, limit)
+
+	return sqlQuery, args
+}
+
+func buildSemanticQuery(query, category string, embedding []float64, limit int) (string, []interface{}) {
+	sqlQuery := `
+		SELECT id, category, content, source, created_at, is_active, priority, expires_at,
+			1 - (embedding <=> $1::vector) AS rank
+		FROM context
+		WHERE is_active = true
+		AND embedding IS NOT NULL
+	`
+	args := []interface{}{formatVector(embedding)}
+	argPos := 2
+
+	if category != "" {
+		sqlQuery += fmt.Sprintf(" AND category = $%d", argPos)
+		args = append(args, category)
+		argPos++
+	}
+
+	sqlQuery += fmt.Sprintf(" ORDER BY embedding <=> $1::vector LIMIT $%d", argPos)
+	args = append(args, limit)
+
+	return sqlQuery, args
+}
+
+func buildHybridQuery(query, category string, embedding []float64, limit int) (string, []interface{}) {
+	weight := hybridTextWeight()
+
+	sqlQuery := `
+		SELECT id, category, content, source, created_at, is_active, priority, expires_at,
+			($3 * ts_rank_cd(tsv, websearch_to_tsquery('english', $1), 32)) +
+			((1 - $3) * (1 - (embedding <=> $2::vector))) AS rank
+		FROM context
+		WHERE is_active = true
+		AND embedding IS NOT NULL
+		AND tsv @@ websearch_to_tsquery('english', $1)
+	`
+	args := []interface{}{query, formatVector(embedding), weight}
+	argPos := 4
+
+	if category != "" {
+		sqlQuery += fmt.Sprintf(" AND category = $%d", argPos)
+		args = append(args, category)
+		argPos++
+	}
+
+	sqlQuery += fmt.Sprintf(" ORDER BY rank DESC, created_at DESC LIMIT $%d", argPos)
+	args = append(args, limit)
+
+	return sqlQuery, args
+}
+
+// hybridTextWeight is how much of the combined rank comes from the
+// full-text score versus the vector similarity score; configurable
+// because the right balance depends on the embedding model in use.
+func hybridTextWeight() float64 {
+	raw := getEnv("SEARCH_HYBRID_TEXT_WEIGHT", "0.5")
+	weight, err := strconv.ParseFloat(raw, 64)
+	if err != nil || weight < 0 || weight > 1 {
+		return 0.5
+	}
+	return weight
+}
+
+func formatVector(embedding []float64) string {
+	parts := make([]string, len(embedding))
+	for i, v := range embedding {
+		parts[i] = strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
+
+// resolveEmbedding returns the caller-supplied embedding if present,
+// otherwise fetches one from EMBEDDING_SERVICE_URL for the given query
+// text.
+func (a *App) resolveEmbedding(supplied []float64, query string) ([]float64, error) {
+	if len(supplied) > 0 {
+		return supplied, nil
+	}
+
+	url := getEnv("EMBEDDING_SERVICE_URL", "")
+	if url == "" {
+		return nil, fmt.Errorf("no embedding supplied and EMBEDDING_SERVICE_URL is not configured")
+	}
+
+	return fetchEmbedding(url, query)
+}
+
+func fetchEmbedding(url, query string) ([]float64, error) {
+	body, err := json.Marshal(map[string]string{"text": query})
+	if err != nil {
+		return nil, err
+	}
+
+	client := http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embedding service returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Embedding []float64 `json:"embedding"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decoding embedding service response: %w", err)
+	}
+
+	return result.Embedding, nil
+}
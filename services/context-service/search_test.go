@@ -0,0 +1,88 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolveSearchMode(t *testing.T) {
+	full := searchCapabilities{fullText: true, vector: true}
+	textOnly := searchCapabilities{fullText: true}
+	vectorOnly := searchCapabilities{vector: true}
+	none := searchCapabilities{}
+
+	cases := []struct {
+		name      string
+		requested string
+		caps      searchCapabilities
+		want      searchMode
+	}{
+		{"hybrid with full support", "hybrid", full, searchModeHybrid},
+		{"hybrid falls back to semantic", "hybrid", vectorOnly, searchModeSemantic},
+		{"hybrid falls back to ilike", "hybrid", none, searchModeILIKE},
+		{"semantic with vector support", "semantic", full, searchModeSemantic},
+		{"semantic falls back to text", "semantic", textOnly, searchModeText},
+		{"semantic falls back to ilike", "semantic", none, searchModeILIKE},
+		{"text with support", "text", textOnly, searchModeText},
+		{"text falls back to ilike", "text", none, searchModeILIKE},
+		{"unspecified mode defaults to text when available", "", full, searchModeText},
+		{"unspecified mode falls back to ilike", "", none, searchModeILIKE},
+		{"unknown mode forces ilike even when text is available", "bogus", full, searchModeILIKE},
+		{"case insensitive", "HYBRID", full, searchModeHybrid},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := resolveSearchMode(c.requested, c.caps)
+			if got != c.want {
+				t.Errorf("resolveSearchMode(%q, %+v) = %q, want %q", c.requested, c.caps, got, c.want)
+			}
+		})
+	}
+}
+
+func TestBuildSearchQueryILIKE(t *testing.T) {
+	sql, args := buildSearchQuery(searchModeILIKE, "hello", "notes", nil, 10)
+	if !strings.Contains(sql, "ILIKE $1") {
+		t.Errorf("expected ILIKE clause, got: %s", sql)
+	}
+	if !strings.Contains(sql, "category = $2") {
+		t.Errorf("expected category filter at $2, got: %s", sql)
+	}
+	if len(args) != 3 || args[0] != "%hello%" || args[1] != "notes" || args[2] != 10 {
+		t.Errorf("args = %v, want [%%hello%% notes 10]", args)
+	}
+}
+
+func TestBuildSearchQueryILIKENoCategory(t *testing.T) {
+	sql, args := buildSearchQuery(searchModeILIKE, "hello", "", nil, 10)
+	if strings.Contains(sql, "AND category") {
+		t.Errorf("expected no category filter, got: %s", sql)
+	}
+	if len(args) != 2 || args[1] != 10 {
+		t.Errorf("args = %v, want [%%hello%% 10]", args)
+	}
+}
+
+func TestBuildSearchQueryText(t *testing.T) {
+	sql, args := buildSearchQuery(searchModeText, "hello", "", nil, 5)
+	if !strings.Contains(sql, "websearch_to_tsquery") {
+		t.Errorf("expected tsquery clause, got: %s", sql)
+	}
+	if len(args) != 2 || args[0] != "hello" || args[1] != 5 {
+		t.Errorf("args = %v, want [hello 5]", args)
+	}
+}
+
+func TestBuildHybridQuery(t *testing.T) {
+	sql, args := buildHybridQuery("hello", "notes", []float64{0.1, 0.2}, 20)
+	if !strings.Contains(sql, "category = $4") {
+		t.Errorf("expected category filter at $4, got: %s", sql)
+	}
+	if len(args) != 5 {
+		t.Fatalf("args = %v, want 5 entries", args)
+	}
+	if args[0] != "hello" || args[1] != "[0.1,0.2]" || args[3] != "notes" || args[4] != 20 {
+		t.Errorf("args = %v, want [hello [0.1,0.2] <weight> notes 20]", args)
+	}
+}
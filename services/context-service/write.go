@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// createContextHandler enqueues a new context row for ingestion. The
+// response is 202 Accepted rather than 201 Created because the row isn't
+// written to Postgres synchronously - see IngestWorkerPool.
+func (a *App) createContextHandler(w http.ResponseWriter, r *http.Request) {
+	var c Context
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := validateContext(&c); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := a.queue.Push(ctx, QueueItem{Op: "insert", Context: &c, EnqueuedAt: time.Now()}); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to enqueue context: "+err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusAccepted, map[string]string{"status": "queued"})
+}
+
+// batchContextHandler enqueues a batch of new context rows. The whole
+// batch is validated up front, so a validation error never leaves some
+// items queued and others rejected. Items are pushed one at a time: if a
+// push fails partway, the items before it stay queued and the request
+// returns 500.
+func (a *App) batchContextHandler(w http.ResponseWriter, r *http.Request) {
+	var items []Context
+	if err := json.NewDecoder(r.Body).Decode(&items); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if len(items) == 0 {
+		respondError(w, http.StatusBadRequest, "Request body must be a non-empty array")
+		return
+	}
+
+	for i := range items {
+		if err := validateContext(&items[i]); err != nil {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("item %d: %v", i, err))
+			return
+		}
+	}
+
+	for i := range items {
+		if err := a.queue.Push(ctx, QueueItem{Op: "insert", Context: &items[i], EnqueuedAt: time.Now()}); err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to enqueue batch: "+err.Error())
+			return
+		}
+	}
+
+	respondJSON(w, http.StatusAccepted, map[string]interface{}{"status": "queued", "count": len(items)})
+}
+
+// updateContextHandler enqueues a patch to an existing context row. Only
+// fields present in the request body are changed; the worker applies the
+// patch as a COALESCE update against the row's current state at commit
+// time (see IngestWorkerPool.process), not a snapshot read here, so two
+// concurrent patches to the same id can't clobber each other.
+func (a *App) updateContextHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid context id")
+		return
+	}
+
+	var patch contextPatch
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := validateContextPatch(&patch); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := a.queue.Push(ctx, QueueItem{Op: "update", ID: id, Patch: &patch, EnqueuedAt: time.Now()}); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to enqueue update: "+err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusAccepted, map[string]string{"status": "queued"})
+}
+
+// deleteContextHandler enqueues a soft-delete (is_active = false) of a
+// context row; rows are never hard-deleted through the API.
+func (a *App) deleteContextHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid context id")
+		return
+	}
+
+	if err := a.queue.Push(ctx, QueueItem{Op: "delete", ID: id, EnqueuedAt: time.Now()}); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to enqueue delete: "+err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusAccepted, map[string]string{"status": "queued"})
+}